@@ -12,8 +12,9 @@ func main() {
 	ds := plugin.NewJsonDatasource()
 
 	opts := datasource.ServeOpts{
-		QueryDataHandler:   ds,
-		CheckHealthHandler: ds,
+		QueryDataHandler:    ds,
+		CheckHealthHandler:  ds,
+		CallResourceHandler: ds,
 	}
 
 	if err := datasource.Serve(opts); err != nil {
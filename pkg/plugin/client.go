@@ -0,0 +1,161 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	sdkhttpclient "github.com/grafana/grafana-plugin-sdk-go/backend/httpclient"
+)
+
+// jsonData mirrors the subset of DataSourceInstanceSettings.JSONData this
+// plugin reads to build the upstream HTTP client and to bound retries,
+// query concurrency and per-query timeouts.
+type jsonData struct {
+	TLSSkipVerify        bool   `json:"tlsSkipVerify"`
+	TLSAuth              bool   `json:"tlsAuth"`
+	TLSAuthWithCACert    bool   `json:"tlsAuthWithCACert"`
+	TimeoutSeconds       int    `json:"timeout"`
+	KeepAliveSeconds     int    `json:"keepAlive"`
+	HTTPMethod           string `json:"httpMethod"`
+	MaxRetries           int    `json:"maxRetries"`
+	MaxConcurrentQueries int    `json:"maxConcurrentQueries"`
+	QueryTimeoutSeconds  int    `json:"queryTimeoutSeconds"`
+}
+
+const (
+	defaultTimeout         = 30 * time.Second
+	defaultKeepAlive       = 30 * time.Second
+	defaultMaxAttempts     = 3
+	defaultQueryTimeoutCap = 30 * time.Second
+)
+
+// parseJSONData unmarshals settings.JSONData into a jsonData, leaving
+// fields at their zero value when JSONData is empty.
+func parseJSONData(settings backend.DataSourceInstanceSettings) (jsonData, error) {
+	var jd jsonData
+	if len(settings.JSONData) > 0 {
+		if err := json.Unmarshal(settings.JSONData, &jd); err != nil {
+			return jd, fmt.Errorf("unmarshal jsonData: %w", err)
+		}
+	}
+	return jd, nil
+}
+
+// maxAttempts returns the configured retry attempt budget for requests to
+// the upstream server, defaulting when unset.
+func (jd jsonData) maxAttempts() int {
+	if jd.MaxRetries > 0 {
+		return jd.MaxRetries
+	}
+	return defaultMaxAttempts
+}
+
+// maxConcurrentQueries returns the configured cap on how many queries from
+// a single QueryData call may run against the upstream server at once,
+// defaulting to one per CPU.
+func (jd jsonData) maxConcurrentQueries() int {
+	if jd.MaxConcurrentQueries > 0 {
+		return jd.MaxConcurrentQueries
+	}
+	return runtime.NumCPU()
+}
+
+// queryTimeoutCap returns the configured upper bound on how long a single
+// query may run, defaulting when unset.
+func (jd jsonData) queryTimeoutCap() time.Duration {
+	if jd.QueryTimeoutSeconds > 0 {
+		return time.Duration(jd.QueryTimeoutSeconds) * time.Second
+	}
+	return defaultQueryTimeoutCap
+}
+
+// queryMethod returns the HTTP method to use for /query requests,
+// defaulting to POST for anything other than an explicit "GET".
+func (jd jsonData) queryMethod() string {
+	if strings.EqualFold(jd.HTTPMethod, http.MethodGet) {
+		return http.MethodGet
+	}
+	return http.MethodPost
+}
+
+// newHTTPClient builds the *http.Client used to talk to an instance's
+// upstream SimpleJson server, honoring TLS settings, basic/bearer auth,
+// custom headers and timeouts configured on the datasource.
+func newHTTPClient(settings backend.DataSourceInstanceSettings, jd jsonData) (*http.Client, error) {
+	timeout := defaultTimeout
+	if jd.TimeoutSeconds > 0 {
+		timeout = time.Duration(jd.TimeoutSeconds) * time.Second
+	}
+	keepAlive := defaultKeepAlive
+	if jd.KeepAliveSeconds > 0 {
+		keepAlive = time.Duration(jd.KeepAliveSeconds) * time.Second
+	}
+
+	opts := sdkhttpclient.Options{
+		Timeouts: &sdkhttpclient.TimeoutOptions{
+			Timeout:               timeout,
+			KeepAlive:             keepAlive,
+			TLSHandshakeTimeout:   10 * time.Second,
+			ExpectContinueTimeout: 1 * time.Second,
+			MaxIdleConns:          100,
+			IdleConnTimeout:       90 * time.Second,
+		},
+		TLS: &sdkhttpclient.TLSOptions{
+			InsecureSkipVerify: jd.TLSSkipVerify,
+		},
+		Header: customHeaders(settings),
+	}
+
+	if jd.TLSAuthWithCACert {
+		opts.TLS.CACertificate = settings.DecryptedSecureJSONData["tlsCACert"]
+	}
+	if jd.TLSAuth {
+		opts.TLS.ClientCertificate = settings.DecryptedSecureJSONData["tlsClientCert"]
+		opts.TLS.ClientKey = settings.DecryptedSecureJSONData["tlsClientKey"]
+	}
+
+	if password, ok := settings.DecryptedSecureJSONData["basicAuthPassword"]; ok && password != "" {
+		opts.BasicAuth = &sdkhttpclient.BasicAuthOptions{
+			User:     settings.BasicAuthUser,
+			Password: password,
+		}
+	}
+
+	if token, ok := settings.DecryptedSecureJSONData["bearerToken"]; ok && token != "" {
+		opts.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	return sdkhttpclient.New(opts)
+}
+
+// customHeaders reads the httpHeaderName{N}/httpHeaderValue{N} pairs
+// Grafana stores for custom headers, with names in JSONData and values
+// encrypted in SecureJSONData.
+func customHeaders(settings backend.DataSourceInstanceSettings) http.Header {
+	headers := http.Header{}
+
+	var raw map[string]interface{}
+	if err := json.Unmarshal(settings.JSONData, &raw); err != nil {
+		return headers
+	}
+
+	for i := 1; ; i++ {
+		nameKey := "httpHeaderName" + strconv.Itoa(i)
+		name, ok := raw[nameKey].(string)
+		if !ok || name == "" {
+			break
+		}
+
+		if value, ok := settings.DecryptedSecureJSONData["httpHeaderValue"+strconv.Itoa(i)]; ok {
+			headers.Set(name, value)
+		}
+	}
+
+	return headers
+}
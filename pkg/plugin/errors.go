@@ -0,0 +1,120 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const baseBackoff = 100 * time.Millisecond
+
+// upstreamError means the SimpleJson server itself responded with a
+// status code other than 200 OK.
+type upstreamError struct {
+	status int
+}
+
+func (e *upstreamError) Error() string {
+	return fmt.Sprintf("upstream error: status %d", e.status)
+}
+
+// networkError means the request never got a response from the upstream
+// server, e.g. a dial failure or a connection dropped mid-read.
+type networkError struct {
+	cause error
+}
+
+func (e *networkError) Error() string {
+	return fmt.Sprintf("network error: %s", e.cause)
+}
+
+func (e *networkError) Unwrap() error {
+	return e.cause
+}
+
+// pluginError means the failure originated in this plugin rather than the
+// upstream server, e.g. a malformed request that could not be built.
+type pluginError struct {
+	cause error
+}
+
+func (e *pluginError) Error() string {
+	return fmt.Sprintf("plugin error: %s", e.cause)
+}
+
+func (e *pluginError) Unwrap() error {
+	return e.cause
+}
+
+// parseError means the upstream server responded, but its response body
+// was not a SimpleJson payload this plugin could parse.
+type parseError struct {
+	cause error
+}
+
+func (e *parseError) Error() string {
+	return fmt.Sprintf("parse error: %s", e.cause)
+}
+
+func (e *parseError) Unwrap() error {
+	return e.cause
+}
+
+// isRetryableNetworkError reports whether err represents a transient
+// transport failure worth retrying: a temporary/timeout net.OpError, an
+// EOF on response read, a reset connection, or a closed idle connection
+// raced by the server.
+func isRetryableNetworkError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, io.EOF) {
+		return true
+	}
+
+	msg := err.Error()
+	if strings.Contains(msg, "connection reset by peer") ||
+		strings.Contains(msg, "use of closed network connection") ||
+		strings.Contains(msg, "broken pipe") {
+		return true
+	}
+
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		return opErr.Temporary() || opErr.Timeout()
+	}
+
+	return false
+}
+
+// isRetryableStatus reports whether status is a transient upstream
+// failure worth retrying.
+func isRetryableStatus(status int) bool {
+	switch status {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+// sleepBackoff waits out the exponential backoff (with jitter) for the
+// given attempt number, returning early with ctx.Err() if ctx is done
+// first.
+func sleepBackoff(ctx context.Context, attempt int) error {
+	backoff := baseBackoff << uint(attempt-1)
+	backoff += time.Duration(rand.Int63n(int64(backoff)))
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(backoff):
+		return nil
+	}
+}
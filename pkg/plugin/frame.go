@@ -0,0 +1,84 @@
+package plugin
+
+import (
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// newTimeSeriesFrame turns a timeseries target response into a data.Frame
+// with a time field and a single float64 field named after the target.
+func newTimeSeriesFrame(target TargetResponseDTO) *data.Frame {
+	times := make([]time.Time, len(target.DataPoints))
+	values := make([]float64, len(target.DataPoints))
+
+	for i, point := range target.DataPoints {
+		values[i] = point[0]
+		times[i] = time.Unix(0, int64(point[1])*int64(time.Millisecond))
+	}
+
+	return data.NewFrame(target.Target,
+		data.NewField("time", nil, times),
+		data.NewField(target.Target, nil, values),
+	)
+}
+
+// newTableFrame turns a table target response into a data.Frame where each
+// declared column becomes a field whose Go type matches the column's
+// declared "type". A cell that fails its type assertion is left as a
+// field-level null instead of panicking.
+func newTableFrame(target TargetResponseDTO) *data.Frame {
+	frame := data.NewFrame(target.Target)
+
+	for col, column := range target.Columns {
+		switch column.Type {
+		case "time":
+			values := make([]*time.Time, len(target.Rows))
+			for row, cells := range target.Rows {
+				if ms, ok := cellFloat64(cells, col); ok {
+					t := time.Unix(0, int64(ms)*int64(time.Millisecond))
+					values[row] = &t
+				}
+			}
+			frame.Fields = append(frame.Fields, data.NewField(column.Text, nil, values))
+		case "number":
+			values := make([]*float64, len(target.Rows))
+			for row, cells := range target.Rows {
+				if v, ok := cellFloat64(cells, col); ok {
+					values[row] = &v
+				}
+			}
+			frame.Fields = append(frame.Fields, data.NewField(column.Text, nil, values))
+		default:
+			values := make([]*string, len(target.Rows))
+			for row, cells := range target.Rows {
+				if v, ok := cellString(cells, col); ok {
+					values[row] = &v
+				}
+			}
+			frame.Fields = append(frame.Fields, data.NewField(column.Text, nil, values))
+		}
+	}
+
+	return frame
+}
+
+// cellFloat64 returns the cell at col as a float64, or false if the row is
+// too short or the cell is not a number.
+func cellFloat64(row []interface{}, col int) (float64, bool) {
+	if col >= len(row) {
+		return 0, false
+	}
+	v, ok := row[col].(float64)
+	return v, ok
+}
+
+// cellString returns the cell at col as a string, or false if the row is
+// too short or the cell is not a string.
+func cellString(row []interface{}, col int) (string, bool) {
+	if col >= len(row) {
+		return "", false
+	}
+	s, ok := row[col].(string)
+	return s, ok
+}
@@ -0,0 +1,92 @@
+package plugin
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+)
+
+func TestParseQueryResponseTimeseries(t *testing.T) {
+	body, err := os.ReadFile("testdata/timeseries_response.json")
+	if err != nil {
+		t.Fatalf("read fixture: %v", err)
+	}
+
+	ds := &JsonDatasource{}
+	query := &backend.DataQuery{RefID: "A"}
+
+	resp := ds.parseQueryResponse(query, body)
+	if resp.Error != nil {
+		t.Fatalf("unexpected error: %v", resp.Error)
+	}
+	if len(resp.Frames) != 1 {
+		t.Fatalf("expected 1 frame, got %d", len(resp.Frames))
+	}
+
+	frame := resp.Frames[0]
+	if frame.RefID != "A" {
+		t.Errorf("expected RefID A, got %q", frame.RefID)
+	}
+	if frame.Name != "upper_75" {
+		t.Errorf("expected frame name upper_75, got %q", frame.Name)
+	}
+	if len(frame.Fields) != 2 {
+		t.Fatalf("expected 2 fields, got %d", len(frame.Fields))
+	}
+	if frame.Fields[1].Name != "upper_75" {
+		t.Errorf("expected value field named upper_75, got %q", frame.Fields[1].Name)
+	}
+
+	wantTime := time.Unix(0, 1450754160000*int64(time.Millisecond))
+	gotTime := frame.Fields[0].At(0).(time.Time)
+	if !gotTime.Equal(wantTime) {
+		t.Errorf("expected time %v, got %v", wantTime, gotTime)
+	}
+
+	gotValue := frame.Fields[1].At(0).(float64)
+	if gotValue != 622 {
+		t.Errorf("expected value 622, got %v", gotValue)
+	}
+}
+
+func TestParseQueryResponseTable(t *testing.T) {
+	body, err := os.ReadFile("testdata/table_response.json")
+	if err != nil {
+		t.Fatalf("read fixture: %v", err)
+	}
+
+	ds := &JsonDatasource{}
+	query := &backend.DataQuery{RefID: "B"}
+
+	resp := ds.parseQueryResponse(query, body)
+	if resp.Error != nil {
+		t.Fatalf("unexpected error: %v", resp.Error)
+	}
+	if len(resp.Frames) != 1 {
+		t.Fatalf("expected 1 frame, got %d", len(resp.Frames))
+	}
+
+	frame := resp.Frames[0]
+	if len(frame.Fields) != 3 {
+		t.Fatalf("expected 3 fields, got %d", len(frame.Fields))
+	}
+
+	countryField := frame.Fields[1]
+	if got := *countryField.At(0).(*string); got != "SE" {
+		t.Errorf("expected country SE, got %q", got)
+	}
+
+	numberField := frame.Fields[2]
+	if got := *numberField.At(0).(*float64); got != 123 {
+		t.Errorf("expected number 123, got %v", got)
+	}
+	if got := numberField.At(1).(*float64); got != nil {
+		t.Errorf("expected nil for non-numeric cell, got %v", *got)
+	}
+
+	if got := countryField.At(2).(*string); got != nil {
+		t.Errorf("expected nil for non-string cell, got %v", *got)
+	}
+}
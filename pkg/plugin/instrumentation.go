@@ -0,0 +1,85 @@
+package plugin
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel"
+)
+
+// tracer emits one span per upstream query, consumed by whatever
+// OpenTelemetry exporter Grafana's plugin host is configured with.
+var tracer = otel.Tracer("github.com/onukura/simple-json-backend-datasource")
+
+// requestTotal counts upstream queries by endpoint, HTTP status and
+// error source, and is exposed to Grafana's CollectMetrics endpoint via
+// the default Prometheus registry/gatherer. It is incremented once per
+// query however many attempts the query took; resource calls are not
+// counted.
+var requestTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "plugin_request_total",
+		Help: "Total number of upstream SimpleJson queries, by endpoint, status and error source.",
+	},
+	[]string{"endpoint", "status", "source"},
+)
+
+func init() {
+	prometheus.MustRegister(requestTotal)
+}
+
+// errorSource classifies err as a downstream (upstream server) failure or
+// a plugin (internal) bug, the two sources backend.DataResponse.Error can
+// be tagged with.
+func errorSource(err error) backend.ErrorSource {
+	if err == nil {
+		return ""
+	}
+	var upErr *upstreamError
+	var netErr *networkError
+	var parseErr *parseError
+	if errors.As(err, &upErr) || errors.As(err, &netErr) || errors.As(err, &parseErr) {
+		return backend.ErrorSourceDownstream
+	}
+	return backend.ErrorSourcePlugin
+}
+
+// statusFromErr extracts the upstream HTTP status code carried by err, or
+// 0 if the request never reached the server.
+func statusFromErr(err error) int {
+	var upErr *upstreamError
+	if errors.As(err, &upErr) {
+		return upErr.status
+	}
+	return 0
+}
+
+// requestStatusLabel is the coarse "ok"/"error" request_status span
+// attribute and log field.
+func requestStatusLabel(err error) string {
+	if err == nil {
+		return "ok"
+	}
+	return "error"
+}
+
+// recordRequest increments the plugin_request_total counter and emits a
+// single structured log line summarizing one upstream request.
+func recordRequest(refID, endpoint string, status int, source backend.ErrorSource, duration time.Duration) {
+	sourceLabel := string(source)
+	if sourceLabel == "" {
+		sourceLabel = "none"
+	}
+	requestTotal.WithLabelValues(endpoint, strconv.Itoa(status), sourceLabel).Inc()
+
+	backend.Logger.Info("upstream request completed",
+		"refId", refID,
+		"endpoint", endpoint,
+		"status", status,
+		"source", sourceLabel,
+		"duration", duration,
+	)
+}
@@ -0,0 +1,40 @@
+package plugin
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+)
+
+func TestErrorSourceClassification(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want backend.ErrorSource
+	}{
+		{"nil", nil, ""},
+		{"upstream", &upstreamError{status: 502}, backend.ErrorSourceDownstream},
+		{"network", &networkError{cause: errors.New("dial tcp: timeout")}, backend.ErrorSourceDownstream},
+		{"parse", &parseError{cause: errors.New("unexpected end of JSON input")}, backend.ErrorSourceDownstream},
+		{"plugin", &pluginError{cause: errors.New("bad request")}, backend.ErrorSourcePlugin},
+		{"other", errors.New("boom"), backend.ErrorSourcePlugin},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := errorSource(tt.err); got != tt.want {
+				t.Errorf("errorSource(%v) = %q, want %q", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStatusFromErr(t *testing.T) {
+	if got := statusFromErr(&upstreamError{status: 503}); got != 503 {
+		t.Errorf("expected 503, got %d", got)
+	}
+	if got := statusFromErr(errors.New("boom")); got != 0 {
+		t.Errorf("expected 0, got %d", got)
+	}
+}
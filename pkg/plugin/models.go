@@ -0,0 +1,39 @@
+package plugin
+
+import (
+	"net/http"
+
+	"github.com/bitly/go-simplejson"
+)
+
+// RemoteDatasourceRequest bundles the outgoing HTTP request to the
+// upstream SimpleJson server. The body is kept as raw bytes, rather than
+// an already-built *http.Request, so makeHttpRequest can rebuild a fresh
+// request for each retry attempt.
+type RemoteDatasourceRequest struct {
+	queryType string
+	method    string
+	url       string
+	headers   http.Header
+	body      []byte
+	queries   *simplejson.Json
+}
+
+// TargetResponseDTO represents a single target element returned by a
+// SimpleJson backend in response to a POST /query request. The shape
+// varies depending on whether the target is a timeseries or a table
+// result, so most fields are only populated for one of the two.
+type TargetResponseDTO struct {
+	Target     string          `json:"target"`
+	DataPoints [][2]float64    `json:"datapoints"`
+	Columns    []ColumnDTO     `json:"columns"`
+	Rows       [][]interface{} `json:"rows"`
+	Type       string          `json:"type"`
+}
+
+// ColumnDTO describes a single column of a table response, including the
+// declared value type used to pick the data.Frame field type.
+type ColumnDTO struct {
+	Text string `json:"text"`
+	Type string `json:"type"`
+}
@@ -1,13 +1,12 @@
 package plugin
 
 import (
+	"bytes"
 	"context"
-	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
-	"strings"
 	"sync"
 	"time"
 
@@ -16,6 +15,8 @@ import (
 	"github.com/grafana/grafana-plugin-sdk-go/backend/datasource"
 	"github.com/grafana/grafana-plugin-sdk-go/backend/instancemgmt"
 	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"golang.org/x/net/context/ctxhttp"
 )
 
@@ -31,6 +32,7 @@ import (
 var (
 	_ backend.QueryDataHandler      = (*JsonDatasource)(nil)
 	_ backend.CheckHealthHandler    = (*JsonDatasource)(nil)
+	_ backend.CallResourceHandler   = (*JsonDatasource)(nil)
 	_ instancemgmt.InstanceDisposer = (*JsonDatasource)(nil)
 )
 
@@ -42,15 +44,26 @@ func NewJsonDatasource() *JsonDatasource {
 	}
 }
 
-type JsonDatasourceInstance struct {
-	dsInfo *backend.DataSourceInstanceSettings
-}
-
-func newJsonDatasourceInstance(settings backend.DataSourceInstanceSettings) (instancemgmt.Instance, error) {
+func newJsonDatasourceInstance(_ context.Context, settings backend.DataSourceInstanceSettings) (instancemgmt.Instance, error) {
 	backend.Logger.Debug("Initializing new data source instance")
 
-	return &JsonDatasourceInstance{
-		dsInfo: &settings,
+	jd, err := parseJSONData(settings)
+	if err != nil {
+		return nil, err
+	}
+
+	httpClient, err := newHTTPClient(settings, jd)
+	if err != nil {
+		return nil, fmt.Errorf("build http client: %w", err)
+	}
+
+	return &dataSourceInstance{
+		settings:        settings,
+		httpClient:      httpClient,
+		maxAttempts:     jd.maxAttempts(),
+		concurrency:     jd.maxConcurrentQueries(),
+		queryTimeoutCap: jd.queryTimeoutCap(),
+		queryMethod:     jd.queryMethod(),
 	}, nil
 }
 
@@ -65,8 +78,8 @@ func (ds *JsonDatasource) Dispose() {
 	// Clean up datasource instance resources.
 }
 
-func (ds *JsonDatasource) getInstance(ctx backend.PluginContext) (*dataSourceInstance, error) {
-	instance, err := ds.im.Get(ctx)
+func (ds *JsonDatasource) getInstance(ctx context.Context, pluginContext backend.PluginContext) (*dataSourceInstance, error) {
+	instance, err := ds.im.Get(ctx, pluginContext)
 	if err != nil {
 		backend.Logger.Error(err.Error())
 		return nil, err
@@ -76,17 +89,30 @@ func (ds *JsonDatasource) getInstance(ctx backend.PluginContext) (*dataSourceIns
 
 // dataSourceInstance represents a single instance of this data source.
 type dataSourceInstance struct {
-	settings backend.DataSourceInstanceSettings
+	settings        backend.DataSourceInstanceSettings
+	httpClient      *http.Client
+	maxAttempts     int
+	concurrency     int
+	queryTimeoutCap time.Duration
+	queryMethod     string
+}
+
+var _ instancemgmt.InstanceDisposer = (*dataSourceInstance)(nil)
+
+// Dispose closes idle connections held by the instance's HTTP client when
+// the instance manager replaces it with a freshly configured one.
+func (i *dataSourceInstance) Dispose() {
+	i.httpClient.CloseIdleConnections()
 }
 
 // CheckHealth handles health checks sent from Grafana to the plugin.
 // The main use case for these health checks is the test button on the
 // datasource configuration page which allows users to verify that
 // a datasource is working as expected.
-func (ds *JsonDatasource) CheckHealth(_ context.Context, req *backend.CheckHealthRequest) (*backend.CheckHealthResult, error) {
+func (ds *JsonDatasource) CheckHealth(ctx context.Context, req *backend.CheckHealthRequest) (*backend.CheckHealthResult, error) {
 	res := &backend.CheckHealthResult{}
 
-	_, err := ds.im.Get(req.PluginContext)
+	_, err := ds.im.Get(ctx, req.PluginContext)
 	if err != nil {
 		res.Status = backend.HealthStatusError
 		res.Message = "Error getting datasource instance"
@@ -107,26 +133,35 @@ func (ds *JsonDatasource) QueryData(ctx context.Context, req *backend.QueryDataR
 	// create response struct
 	responses := backend.NewQueryDataResponse()
 
-	backend.Logger.Info("ok 1")
-	instance, err := ds.getInstance(req.PluginContext)
+	instance, err := ds.getInstance(ctx, req.PluginContext)
 	if err != nil {
-		backend.Logger.Error(err.Error())
+		backend.Logger.Error("failed to get datasource instance", "err", err)
 		return nil, err
 	}
 
-	backend.Logger.Info("QueryData called", "request", req)
+	backend.Logger.Debug("QueryData called", "queries", len(req.Queries), "concurrency", instance.concurrency)
 
-	var wg sync.WaitGroup
+	var (
+		mu  sync.Mutex
+		wg  sync.WaitGroup
+		sem = make(chan struct{}, instance.concurrency)
+	)
 	wg.Add(len(req.Queries))
-	backend.Logger.Info("ok 3")
 
-	// loop over queries and execute them individually.
+	// loop over queries and execute them individually, bounded by sem so
+	// at most instance.concurrency run against the upstream at once.
 	for _, q := range req.Queries {
 		go func(q backend.DataQuery) {
-			backend.Logger.Info("ok 4")
-			res := ds.query(ctx, q, instance)
+			defer wg.Done()
+
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			res := ds.safeQuery(ctx, q, instance)
+
+			mu.Lock()
 			responses.Responses[q.RefID] = res
-			wg.Done()
+			mu.Unlock()
 		}(q)
 	}
 
@@ -136,27 +171,82 @@ func (ds *JsonDatasource) QueryData(ctx context.Context, req *backend.QueryDataR
 	return responses, nil
 }
 
+// safeQuery runs query under a per-query timeout derived from its
+// interval (capped at instance.queryTimeoutCap) and recovers from any
+// panic so one failing query cannot take the rest of the batch down with
+// it.
+func (ds *JsonDatasource) safeQuery(ctx context.Context, query backend.DataQuery, instance *dataSourceInstance) (res backend.DataResponse) {
+	defer func() {
+		if r := recover(); r != nil {
+			backend.Logger.Error("query panicked", "refId", query.RefID, "panic", r)
+			res = backend.DataResponse{
+				Error:       fmt.Errorf("panic: %v", r),
+				ErrorSource: backend.ErrorSourcePlugin,
+			}
+		}
+	}()
+
+	queryCtx, cancel := context.WithTimeout(ctx, queryTimeout(query, instance.queryTimeoutCap))
+	defer cancel()
+
+	return ds.query(queryCtx, query, instance)
+}
+
+// queryTimeout derives a per-query deadline from the query's own interval,
+// capped at the instance's configured maximum.
+func queryTimeout(query backend.DataQuery, limit time.Duration) time.Duration {
+	if query.Interval > 0 && query.Interval < limit {
+		return query.Interval
+	}
+	return limit
+}
+
+// query runs a single query against the upstream server, recording a
+// trace span, a plugin_request_total metric and a structured log line,
+// and tagging any error with its downstream/plugin source.
 func (ds *JsonDatasource) query(ctx context.Context, query backend.DataQuery, instance *dataSourceInstance) backend.DataResponse {
-	backend.Logger.Info("ok query 1")
+	ctx, span := tracer.Start(ctx, "simplejson.query", trace.WithAttributes(
+		attribute.String("refId", query.RefID),
+		attribute.String("target_url", instance.settings.URL),
+	))
+	defer span.End()
+
+	start := time.Now()
+	res, status := ds.doQuery(ctx, query, instance)
+	duration := time.Since(start)
+	source := errorSource(res.Error)
+
+	span.SetAttributes(
+		attribute.String("request_status", requestStatusLabel(res.Error)),
+		attribute.String("status_source", string(source)),
+		attribute.Int("http.status_code", status),
+	)
+	recordRequest(query.RefID, "/query", status, source, duration)
+	backend.Logger.Info("query finished",
+		"refId", query.RefID,
+		"duration", duration,
+		"status", status,
+		"source", source,
+	)
+
+	res.ErrorSource = source
+	return res
+}
+
+// doQuery runs the request/parse pipeline for a single query, returning
+// the upstream HTTP status (0 if the request never reached the server).
+func (ds *JsonDatasource) doQuery(ctx context.Context, query backend.DataQuery, instance *dataSourceInstance) (backend.DataResponse, int) {
 	remoteDsReq, err := ds.createMetricRequest(&query, instance)
 	if err != nil {
-		return backend.DataResponse{Error: err}
+		return backend.DataResponse{Error: err}, 0
 	}
 
-	backend.Logger.Info("ok query 2")
-	body, err := ds.makeHttpRequest(ctx, remoteDsReq)
+	body, err := ds.makeHttpRequest(ctx, remoteDsReq, instance)
 	if err != nil {
-		return backend.DataResponse{Error: err}
+		return backend.DataResponse{Error: err}, statusFromErr(err)
 	}
 
-	backend.Logger.Info("ok query 3")
-	res := ds.parseQueryResponse(&query, body)
-	if res.Error != nil {
-		return backend.DataResponse{Error: err}
-	}
-
-	backend.Logger.Info("ok query 4")
-	return res
+	return ds.parseQueryResponse(&query, body), http.StatusOK
 }
 
 func (ds *JsonDatasource) createMetricRequest(q *backend.DataQuery, instance *dataSourceInstance) (*RemoteDatasourceRequest, error) {
@@ -174,150 +264,100 @@ func (ds *JsonDatasource) createMetricRequest(q *backend.DataQuery, instance *da
 		return nil, err
 	}
 
-	url := instance.settings.URL + "/query"
-	req, err := http.NewRequest(http.MethodPost, url, strings.NewReader(string(body)))
-	if err != nil {
-		return nil, err
-	}
-
-	req.Header.Add("Content-Type", "application/json")
+	headers := http.Header{}
+	headers.Add("Content-Type", "application/json")
 
 	return &RemoteDatasourceRequest{
 		queryType: "query",
-		req:       req,
+		method:    instance.queryMethod,
+		url:       instance.settings.URL + "/query",
+		headers:   headers,
+		body:      body,
 		queries:   jsonQueries,
 	}, nil
 }
 
-func (ds *JsonDatasource) makeHttpRequest(ctx context.Context, remoteDsReq *RemoteDatasourceRequest) ([]byte, error) {
-	res, err := ctxhttp.Do(ctx, httpClient, remoteDsReq.req)
-	if err != nil {
-		return nil, err
+// makeHttpRequest issues remoteDsReq against the upstream server, retrying
+// transient network failures and 502/503/504 responses with bounded
+// exponential backoff. The request body is buffered once on
+// remoteDsReq.body and a fresh *http.Request is built for every attempt,
+// so only idempotent-safe requests should be retried this way.
+func (ds *JsonDatasource) makeHttpRequest(ctx context.Context, remoteDsReq *RemoteDatasourceRequest, instance *dataSourceInstance) ([]byte, error) {
+	maxAttempts := instance.maxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = defaultMaxAttempts
 	}
-	defer res.Body.Close()
 
-	if res.StatusCode != http.StatusOK {
-		msg := fmt.Errorf("invalid status code. status: %v", res.Status)
-		backend.Logger.Error(msg.Error())
-		return nil, msg
+	var lastErr error
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		if attempt > 0 {
+			if err := sleepBackoff(ctx, attempt); err != nil {
+				return nil, err
+			}
+		}
+
+		req, err := http.NewRequestWithContext(ctx, remoteDsReq.method, remoteDsReq.url, bytes.NewReader(remoteDsReq.body))
+		if err != nil {
+			return nil, &pluginError{cause: err}
+		}
+		req.Header = remoteDsReq.headers.Clone()
+
+		body, retryable, err := ds.doRequest(ctx, instance, req)
+		if err == nil {
+			return body, nil
+		}
+
+		lastErr = err
+		if !retryable {
+			return nil, err
+		}
+		backend.Logger.Warn("retrying upstream request", "attempt", attempt+1, "maxAttempts", maxAttempts, "err", err)
+	}
+
+	return nil, lastErr
+}
+
+// doRequest performs a single attempt of req and classifies the outcome:
+// the returned bool reports whether the caller should retry.
+func (ds *JsonDatasource) doRequest(ctx context.Context, instance *dataSourceInstance, req *http.Request) ([]byte, bool, error) {
+	res, err := ctxhttp.Do(ctx, instance.httpClient, req)
+	if err != nil {
+		return nil, isRetryableNetworkError(err), &networkError{cause: err}
 	}
+	defer res.Body.Close()
 
 	body, err := ioutil.ReadAll(res.Body)
 	if err != nil {
-		return nil, err
+		return nil, isRetryableNetworkError(err), &networkError{cause: err}
+	}
+
+	if res.StatusCode != http.StatusOK {
+		upErr := &upstreamError{status: res.StatusCode}
+		return nil, isRetryableStatus(res.StatusCode), upErr
 	}
-	return body, nil
+
+	return body, false, nil
 }
 
 func (ds *JsonDatasource) parseQueryResponse(query *backend.DataQuery, body []byte) backend.DataResponse {
-	//refId := query.RefID
 	response := backend.DataResponse{}
 
-	var responseBody []TargetResponseDTO
-
-	// Unmarshal the JSON into our TargetResponseDTO.
-	response.Error = json.Unmarshal(body, &responseBody)
-	if response.Error != nil {
+	var targets []TargetResponseDTO
+	if err := json.Unmarshal(body, &targets); err != nil {
+		response.Error = &parseError{cause: fmt.Errorf("unmarshal query response: %w", err)}
 		return response
 	}
 
-	// create data frame response.
-	frame := data.NewFrame("response")
-
-	// add fields.
-	// test
-	frame.Fields = append(frame.Fields,
-		data.NewField("time", nil, []time.Time{query.TimeRange.From, query.TimeRange.To}),
-		data.NewField("values", nil, []int64{10, 20}),
-	)
-	//
-	//for i, r := range responseBody {
-	//
-	//	field := *data.Field
-	//	qr := datasource.QueryResult{
-	//		RefId:  refId,
-	//		Series: make([]*datasource.TimeSeries, 0),
-	//		Tables: make([]*datasource.Table, 0),
-	//	}
-	//
-	//	if len(r.Columns) > 0 {
-	//		table := datasource.Table{
-	//			Columns: make([]*datasource.TableColumn, 0),
-	//			Rows:    make([]*datasource.TableRow, 0),
-	//		}
-	//
-	//		for _, c := range r.Columns {
-	//			table.Columns = append(table.Columns, &datasource.TableColumn{
-	//				Name: c.Text,
-	//			})
-	//		}
-	//
-	//		for _, row := range r.Rows {
-	//			values := make([]*datasource.RowValue, 0)
-	//
-	//			for i, cell := range row {
-	//				rv := datasource.RowValue{}
-	//
-	//				switch r.Columns[i].Type {
-	//				case "time":
-	//					if timeValue, ok := cell.(float64); ok {
-	//						rv.Int64Value = int64(timeValue)
-	//					}
-	//					rv.Kind = datasource.RowValue_TYPE_INT64
-	//				case "number":
-	//					if numberValue, ok := cell.(float64); ok {
-	//						rv.Int64Value = int64(numberValue)
-	//					}
-	//					rv.Kind = datasource.RowValue_TYPE_INT64
-	//				case "string":
-	//					if stringValue, ok := cell.(string); ok {
-	//						rv.StringValue = stringValue
-	//					}
-	//					rv.Kind = datasource.RowValue_TYPE_STRING
-	//				default:
-	//					ds.logger.Debug(fmt.Sprintf("failed to parse value %v of type %T", cell, cell))
-	//				}
-	//
-	//				values = append(values, &rv)
-	//			}
-	//
-	//			table.Rows = append(table.Rows, &datasource.TableRow{Values: values})
-	//		}
-	//		field
-	//		qr.Tables = append(qr.Tables, &table)
-	//	} else {
-	//		serie := &datasource.TimeSeries{Name: r.Target}
-	//
-	//		for _, p := range r.DataPoints {
-	//			serie.Points = append(serie.Points, &datasource.Point{
-	//				Timestamp: int64(p[1]),
-	//				Value:     p[0],
-	//			})
-	//		}
-	//
-	//		qr.Series = append(qr.Series, serie)
-	//	}
-	//
-	//	response.Responses[refId] = qr
-	//	//response.Results = append(response.Results, &qr)
-	//}
-
-	// add the frames to the response.
-	response.Frames = append(response.Frames, frame)
+	for _, target := range targets {
+		var frame *data.Frame
+		if target.Type == "table" {
+			frame = newTableFrame(target)
+		} else {
+			frame = newTimeSeriesFrame(target)
+		}
+		frame.RefID = query.RefID
+		response.Frames = append(response.Frames, frame)
+	}
 
 	return response
 }
-
-var httpClient = &http.Client{
-	Transport: &http.Transport{
-		TLSClientConfig: &tls.Config{
-			Renegotiation: tls.RenegotiateFreelyAsClient,
-		},
-		Proxy:                 http.ProxyFromEnvironment,
-		TLSHandshakeTimeout:   10 * time.Second,
-		ExpectContinueTimeout: 1 * time.Second,
-		MaxIdleConns:          100,
-		IdleConnTimeout:       90 * time.Second,
-	},
-	Timeout: time.Second * 30,
-}
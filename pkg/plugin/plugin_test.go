@@ -0,0 +1,83 @@
+package plugin
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+)
+
+func TestQueryTimeout(t *testing.T) {
+	short := backend.DataQuery{Interval: 5 * time.Second}
+	if got := queryTimeout(short, 30*time.Second); got != 5*time.Second {
+		t.Errorf("expected the query interval to win, got %v", got)
+	}
+
+	long := backend.DataQuery{Interval: 60 * time.Second}
+	if got := queryTimeout(long, 30*time.Second); got != 30*time.Second {
+		t.Errorf("expected the cap to win, got %v", got)
+	}
+}
+
+func TestSafeQueryRecoversPanic(t *testing.T) {
+	ds := NewJsonDatasource()
+
+	res := ds.safeQuery(context.Background(), backend.DataQuery{RefID: "A"}, nil)
+
+	if res.Error == nil {
+		t.Fatal("expected a non-nil Error after a recovered panic")
+	}
+	if res.ErrorSource != backend.ErrorSourcePlugin {
+		t.Errorf("expected ErrorSource %q, got %q", backend.ErrorSourcePlugin, res.ErrorSource)
+	}
+}
+
+func TestQueryDataRespectsConcurrencyLimit(t *testing.T) {
+	var inFlight, maxSeen int32
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			seen := atomic.LoadInt32(&maxSeen)
+			if n <= seen || atomic.CompareAndSwapInt32(&maxSeen, seen, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`[]`))
+	}))
+	defer ts.Close()
+
+	ds := NewJsonDatasource()
+	settings := &backend.DataSourceInstanceSettings{
+		URL:      ts.URL,
+		JSONData: []byte(`{"maxConcurrentQueries":2}`),
+	}
+
+	queries := make([]backend.DataQuery, 6)
+	for i := range queries {
+		queries[i] = backend.DataQuery{RefID: string(rune('A' + i)), JSON: []byte(`{}`)}
+	}
+
+	req := &backend.QueryDataRequest{
+		PluginContext: backend.PluginContext{DataSourceInstanceSettings: settings},
+		Queries:       queries,
+	}
+
+	resp, err := ds.QueryData(context.Background(), req)
+	if err != nil {
+		t.Fatalf("QueryData: %v", err)
+	}
+	if len(resp.Responses) != len(queries) {
+		t.Fatalf("expected %d responses, got %d", len(queries), len(resp.Responses))
+	}
+	if got := atomic.LoadInt32(&maxSeen); got > 2 {
+		t.Errorf("expected at most 2 concurrent requests, saw %d", got)
+	}
+}
@@ -0,0 +1,150 @@
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+)
+
+// Resource paths served by CallResource, reached by Grafana's variable
+// editor, annotation editor and ad-hoc filter UI.
+const (
+	resourcePathSearch      = "search"
+	resourcePathAnnotations = "annotations"
+	resourcePathTagKeys     = "tag-keys"
+	resourcePathTagValues   = "tag-values"
+)
+
+// annotationResultDTO is a single annotation entry as returned by a
+// SimpleJson backend's /annotations endpoint.
+type annotationResultDTO struct {
+	Annotation string  `json:"annotation"`
+	Time       float64 `json:"time"`
+	Title      string  `json:"title"`
+	Tags       string  `json:"tags"`
+	Text       string  `json:"text"`
+}
+
+// annotationFrameEntry is the shape Grafana's annotation editor expects,
+// with tags split into a list.
+type annotationFrameEntry struct {
+	Time  float64  `json:"time"`
+	Title string   `json:"title"`
+	Tags  []string `json:"tags,omitempty"`
+	Text  string   `json:"text"`
+}
+
+// CallResource proxies POST /search, /annotations, /tag-keys and
+// /tag-values requests to the upstream SimpleJson server so Grafana's
+// variable editor, annotation editor and ad-hoc filter UI can reach it.
+func (ds *JsonDatasource) CallResource(ctx context.Context, req *backend.CallResourceRequest, sender backend.CallResourceResponseSender) error {
+	instance, err := ds.getInstance(ctx, req.PluginContext)
+	if err != nil {
+		return err
+	}
+
+	switch req.Path {
+	case resourcePathSearch:
+		return ds.proxyJSON(ctx, "/search", req.Body, instance, sender)
+	case resourcePathAnnotations:
+		return ds.handleAnnotations(ctx, req, instance, sender)
+	case resourcePathTagKeys:
+		return ds.proxyJSON(ctx, "/tag-keys", req.Body, instance, sender)
+	case resourcePathTagValues:
+		return ds.proxyJSON(ctx, "/tag-values", req.Body, instance, sender)
+	default:
+		return sender.Send(&backend.CallResourceResponse{
+			Status: http.StatusNotFound,
+			Body:   []byte(fmt.Sprintf("unknown resource path %q", req.Path)),
+		})
+	}
+}
+
+// handleAnnotations proxies to the upstream /annotations endpoint and maps
+// each {annotation, time, title, tags, text} entry into the annotation
+// shape Grafana's annotation editor expects, splitting the comma-separated
+// tags string into a list.
+func (ds *JsonDatasource) handleAnnotations(ctx context.Context, req *backend.CallResourceRequest, instance *dataSourceInstance, sender backend.CallResourceResponseSender) error {
+	body, status, err := ds.forward(ctx, "/annotations", req.Body, instance)
+	if err != nil {
+		return sender.Send(&backend.CallResourceResponse{Status: http.StatusBadGateway, Body: []byte(err.Error())})
+	}
+	if status != http.StatusOK {
+		return sender.Send(&backend.CallResourceResponse{Status: status, Body: body})
+	}
+
+	var results []annotationResultDTO
+	if err := json.Unmarshal(body, &results); err != nil {
+		return sender.Send(&backend.CallResourceResponse{
+			Status: http.StatusBadGateway,
+			Body:   []byte(fmt.Sprintf("unmarshal annotations response: %s", err)),
+		})
+	}
+
+	entries := make([]annotationFrameEntry, 0, len(results))
+	for _, r := range results {
+		entry := annotationFrameEntry{
+			Time:  r.Time,
+			Title: r.Title,
+			Text:  r.Text,
+		}
+		if r.Tags != "" {
+			entry.Tags = strings.Split(r.Tags, ",")
+		}
+		entries = append(entries, entry)
+	}
+
+	out, err := json.Marshal(entries)
+	if err != nil {
+		return sender.Send(&backend.CallResourceResponse{Status: http.StatusInternalServerError, Body: []byte(err.Error())})
+	}
+
+	return sender.Send(&backend.CallResourceResponse{
+		Status:  http.StatusOK,
+		Headers: map[string][]string{"Content-Type": {"application/json"}},
+		Body:    out,
+	})
+}
+
+// proxyJSON forwards body to path on the upstream server and relays the
+// response back to Grafana unmodified.
+func (ds *JsonDatasource) proxyJSON(ctx context.Context, path string, body []byte, instance *dataSourceInstance, sender backend.CallResourceResponseSender) error {
+	respBody, status, err := ds.forward(ctx, path, body, instance)
+	if err != nil {
+		return sender.Send(&backend.CallResourceResponse{Status: http.StatusBadGateway, Body: []byte(err.Error())})
+	}
+	return sender.Send(&backend.CallResourceResponse{
+		Status:  status,
+		Headers: map[string][]string{"Content-Type": {"application/json"}},
+		Body:    respBody,
+	})
+}
+
+// forward issues a POST request for path against the instance's upstream
+// URL, returning the response body and status code.
+func (ds *JsonDatasource) forward(ctx context.Context, path string, body []byte, instance *dataSourceInstance) ([]byte, int, error) {
+	url := instance.settings.URL + path
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, 0, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	res, err := instance.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer res.Body.Close()
+
+	respBody, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, 0, err
+	}
+	return respBody, res.StatusCode, nil
+}
@@ -0,0 +1,144 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+)
+
+// callResourceResponseSenderFunc adapts a function to
+// backend.CallResourceResponseSender for use in tests.
+type callResourceResponseSenderFunc func(*backend.CallResourceResponse) error
+
+func (f callResourceResponseSenderFunc) Send(res *backend.CallResourceResponse) error {
+	return f(res)
+}
+
+func callResource(t *testing.T, ts *httptest.Server, path string, body []byte) *backend.CallResourceResponse {
+	t.Helper()
+
+	ds := NewJsonDatasource()
+	req := &backend.CallResourceRequest{
+		PluginContext: backend.PluginContext{
+			DataSourceInstanceSettings: &backend.DataSourceInstanceSettings{URL: ts.URL},
+		},
+		Path:   path,
+		Method: http.MethodPost,
+		Body:   body,
+	}
+
+	var got *backend.CallResourceResponse
+	sender := callResourceResponseSenderFunc(func(res *backend.CallResourceResponse) error {
+		got = res
+		return nil
+	})
+
+	if err := ds.CallResource(context.Background(), req, sender); err != nil {
+		t.Fatalf("CallResource: %v", err)
+	}
+	return got
+}
+
+func TestCallResourceSearch(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/search" {
+			t.Errorf("expected path /search, got %s", r.URL.Path)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`["metric.a","metric.b"]`))
+	}))
+	defer ts.Close()
+
+	res := callResource(t, ts, resourcePathSearch, []byte(`{"target":""}`))
+	if res.Status != http.StatusOK {
+		t.Fatalf("expected 200, got %d", res.Status)
+	}
+	if string(res.Body) != `["metric.a","metric.b"]` {
+		t.Errorf("unexpected body %s", res.Body)
+	}
+}
+
+func TestCallResourceAnnotations(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/annotations" {
+			t.Errorf("expected path /annotations, got %s", r.URL.Path)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`[{"annotation":"deploy","time":1450754160000,"title":"Deploy","tags":"deploy,prod","text":"v1.2.3"}]`))
+	}))
+	defer ts.Close()
+
+	res := callResource(t, ts, resourcePathAnnotations, []byte(`{"annotation":{"name":"deploy"}}`))
+	if res.Status != http.StatusOK {
+		t.Fatalf("expected 200, got %d", res.Status)
+	}
+
+	var entries []annotationFrameEntry
+	if err := json.Unmarshal(res.Body, &entries); err != nil {
+		t.Fatalf("unmarshal response: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("expected 1 entry, got %d", len(entries))
+	}
+
+	entry := entries[0]
+	if entry.Time != 1450754160000 {
+		t.Errorf("expected time 1450754160000, got %v", entry.Time)
+	}
+	if entry.Title != "Deploy" {
+		t.Errorf("expected title Deploy, got %q", entry.Title)
+	}
+	if entry.Text != "v1.2.3" {
+		t.Errorf("expected text v1.2.3, got %q", entry.Text)
+	}
+	if len(entry.Tags) != 2 || entry.Tags[0] != "deploy" || entry.Tags[1] != "prod" {
+		t.Errorf("unexpected tags %v", entry.Tags)
+	}
+}
+
+func TestCallResourceTagKeysAndValues(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case "/tag-keys":
+			_, _ = w.Write([]byte(`[{"type":"string","text":"hostname"}]`))
+		case "/tag-values":
+			_, _ = w.Write([]byte(`[{"text":"web-01"},{"text":"web-02"}]`))
+		default:
+			t.Errorf("unexpected path %s", r.URL.Path)
+		}
+	}))
+	defer ts.Close()
+
+	keysRes := callResource(t, ts, resourcePathTagKeys, nil)
+	if keysRes.Status != http.StatusOK {
+		t.Fatalf("expected 200, got %d", keysRes.Status)
+	}
+	if string(keysRes.Body) != `[{"type":"string","text":"hostname"}]` {
+		t.Errorf("unexpected tag-keys body %s", keysRes.Body)
+	}
+
+	valuesRes := callResource(t, ts, resourcePathTagValues, []byte(`{"key":"hostname"}`))
+	if valuesRes.Status != http.StatusOK {
+		t.Fatalf("expected 200, got %d", valuesRes.Status)
+	}
+	if string(valuesRes.Body) != `[{"text":"web-01"},{"text":"web-02"}]` {
+		t.Errorf("unexpected tag-values body %s", valuesRes.Body)
+	}
+}
+
+func TestCallResourceUnknownPath(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("upstream should not be called for unknown paths")
+	}))
+	defer ts.Close()
+
+	res := callResource(t, ts, "unknown", nil)
+	if res.Status != http.StatusNotFound {
+		t.Fatalf("expected 404, got %d", res.Status)
+	}
+}
@@ -0,0 +1,93 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func newRetryRequest(url string) *RemoteDatasourceRequest {
+	headers := http.Header{}
+	headers.Set("Content-Type", "application/json")
+	return &RemoteDatasourceRequest{
+		queryType: "query",
+		method:    http.MethodPost,
+		url:       url,
+		headers:   headers,
+		body:      []byte(`{"targets":[]}`),
+	}
+}
+
+func TestMakeHttpRequestRetriesTransientStatus(t *testing.T) {
+	var attempts int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`[]`))
+	}))
+	defer ts.Close()
+
+	ds := &JsonDatasource{}
+	instance := &dataSourceInstance{httpClient: ts.Client(), maxAttempts: 3}
+
+	body, err := ds.makeHttpRequest(context.Background(), newRetryRequest(ts.URL), instance)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(body) != "[]" {
+		t.Errorf("unexpected body %q", body)
+	}
+	if attempts != 3 {
+		t.Errorf("expected 3 attempts, got %d", attempts)
+	}
+}
+
+func TestMakeHttpRequestGivesUpAfterMaxAttempts(t *testing.T) {
+	var attempts int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer ts.Close()
+
+	ds := &JsonDatasource{}
+	instance := &dataSourceInstance{httpClient: ts.Client(), maxAttempts: 2}
+
+	_, err := ds.makeHttpRequest(context.Background(), newRetryRequest(ts.URL), instance)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	var upErr *upstreamError
+	if !errors.As(err, &upErr) {
+		t.Fatalf("expected *upstreamError, got %T: %v", err, err)
+	}
+	if attempts != 2 {
+		t.Errorf("expected 2 attempts, got %d", attempts)
+	}
+}
+
+func TestMakeHttpRequestDoesNotRetryNonRetryableStatus(t *testing.T) {
+	var attempts int
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer ts.Close()
+
+	ds := &JsonDatasource{}
+	instance := &dataSourceInstance{httpClient: ts.Client(), maxAttempts: 3}
+
+	_, err := ds.makeHttpRequest(context.Background(), newRetryRequest(ts.URL), instance)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if attempts != 1 {
+		t.Errorf("expected 1 attempt, got %d", attempts)
+	}
+}